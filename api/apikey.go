@@ -0,0 +1,35 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleUserApiKeyRotate handles rotating a user's api key, issuing a new secret under the
+// same prefix and revoking the old one after a grace period so in-flight clients don't break
+// @Summary Rotate User ApiKey
+// @Description Rotate a User's API Key
+// @Tags apikey
+// @Produce  json
+// @Param userId path string true "the user ID"
+// @Param keyId path string true "the api key ID to rotate"
+// @Success 200 object standardJsonResponse{data=model.APIKey}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /users/{userId}/apikeys/{keyId}/rotate [post]
+func (a *api) handleUserApiKeyRotate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		UserID := vars["userId"]
+		KeyID := vars["keyId"]
+
+		NewKey, err := a.db.RotateApiKey(UserID, KeyID)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, NewKey, nil)
+	}
+}
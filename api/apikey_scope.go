@@ -0,0 +1,71 @@
+package api
+
+import (
+	"net/http"
+
+	"github.com/gorilla/mux"
+)
+
+// handleUserApiKeyScopedCreate handles creating a scoped api key for a user, restricting what
+// the key can be used for (e.g. "battle:read", "team:{teamId}:admin", "org:{orgId}:read") so
+// it can be minted for CI or bots without granting full user impersonation
+// @Summary Create Scoped User ApiKey
+// @Description Create a User API Key limited to the provided scopes
+// @Tags apikey
+// @Produce  json
+// @Param userId path string true "the user ID"
+// @Param name body string true "the api key name"
+// @Param scopes body []string true "the scopes to grant the key"
+// @Success 200 object standardJsonResponse{data=model.APIKey}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /users/{userId}/apikeys/scoped [post]
+func (a *api) handleUserApiKeyScopedCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		UserID := vars["userId"]
+		keyVal := getJSONRequestBody(r, w)
+
+		KeyName := keyVal["name"].(string)
+		rawScopes, _ := keyVal["scopes"].([]interface{})
+		Scopes := make([]string, 0, len(rawScopes))
+		for _, s := range rawScopes {
+			if scope, ok := s.(string); ok {
+				Scopes = append(Scopes, scope)
+			}
+		}
+
+		NewKey, err := a.db.GenerateApiKeyWithScopes(UserID, KeyName, Scopes)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, NewKey, nil)
+	}
+}
+
+// handleApiKeyScopesGet gets the scopes granted to an api key
+// @Summary Get ApiKey Scopes
+// @Description Get the scopes granted to an api key
+// @Tags apikey
+// @Produce  json
+// @Param keyId path string true "the api key ID"
+// @Success 200 object standardJsonResponse{data=[]string}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /apikeys/{keyId}/scopes [get]
+func (a *api) handleApiKeyScopesGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		KeyID := vars["keyId"]
+
+		Scopes, err := a.db.GetApiKeyScopes(KeyID)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, Scopes, nil)
+	}
+}
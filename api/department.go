@@ -4,6 +4,7 @@ import (
 	"net/http"
 	"strings"
 
+	"github.com/StevenWeathers/thunderdome-planning-poker/auth/rbac"
 	"github.com/StevenWeathers/thunderdome-planning-poker/model"
 	"github.com/gorilla/mux"
 )
@@ -49,6 +50,35 @@ func (a *api) handleGetOrganizationDepartments() http.HandlerFunc {
 	}
 }
 
+// departmentRole resolves the current user's effective department role. An org ADMIN is
+// treated as ADMIN of every department in the org, mirroring the existing org > department
+// > team inheritance; otherwise it walks up the department hierarchy so an ADMIN on a parent
+// department is treated as ADMIN of all children. It falls back to the role already resolved
+// onto the request context (the pre-hierarchy, non-inheriting value) if neither applies, so a
+// transient DB error degrades to the old flat behavior rather than locking the requester out.
+func (a *api) departmentRole(r *http.Request, DepartmentID string) string {
+	if OrgRole, ok := r.Context().Value(contextKeyOrgRole).(string); ok && OrgRole == "ADMIN" {
+		return "ADMIN"
+	}
+
+	contextRole, _ := r.Context().Value(contextKeyDepartmentRole).(string)
+
+	UserID, ok := r.Context().Value(contextKeyUserID).(string)
+	if !ok {
+		return contextRole
+	}
+
+	InheritedRole, err := a.db.DepartmentRoleInherited(UserID, DepartmentID)
+	if err != nil {
+		return contextRole
+	}
+	if InheritedRole == "" {
+		return contextRole
+	}
+
+	return InheritedRole
+}
+
 // handleGetDepartmentByUser gets a department with user role
 // @Summary Get Department
 // @Description Gets an organization department with users role
@@ -67,10 +97,10 @@ func (a *api) handleGetDepartmentByUser() http.HandlerFunc {
 			return
 		}
 		OrgRole := r.Context().Value(contextKeyOrgRole).(string)
-		DepartmentRole := r.Context().Value(contextKeyDepartmentRole).(string)
 		vars := mux.Vars(r)
 		OrgID := vars["orgId"]
 		DepartmentID := vars["departmentId"]
+		DepartmentRole := a.departmentRole(r, DepartmentID)
 
 		Organization, err := a.db.OrganizationGet(OrgID)
 		if err != nil {
@@ -113,10 +143,17 @@ func (a *api) handleCreateDepartment() http.HandlerFunc {
 			return
 		}
 		vars := mux.Vars(r)
+		OrgID := vars["orgId"]
+		if !a.requireScope(w, r, "org:"+OrgID+":write") {
+			return
+		}
+		OrgRole := r.Context().Value(contextKeyOrgRole).(string)
+		if !a.requireAuthz(w, r, OrgRole, rbac.ActionCreate, rbac.ResourceDepartment) {
+			return
+		}
 		keyVal := getJSONRequestBody(r, w)
 
 		OrgName := keyVal["name"].(string)
-		OrgID := vars["orgId"]
 		NewDepartment, err := a.db.DepartmentCreate(OrgID, OrgName)
 		if err != nil {
 			Failure(w, r, http.StatusInternalServerError, err)
@@ -127,6 +164,103 @@ func (a *api) handleCreateDepartment() http.HandlerFunc {
 	}
 }
 
+// handleGetDepartmentChildren gets a list of the direct sub-departments of a department
+// @Summary Get Department Children
+// @Description Gets a list of a department's direct sub-departments
+// @Tags organization
+// @Produce  json
+// @Param orgId path string true "the organization ID"
+// @Param departmentId path string true "the parent department ID"
+// @Success 200 object standardJsonResponse{data=[]model.Department}
+// @Security ApiKeyAuth
+// @Router /organizations/{orgId}/departments/{departmentId}/departments [get]
+func (a *api) handleGetDepartmentChildren() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.config.OrganizationsEnabled {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
+			return
+		}
+		vars := mux.Vars(r)
+		ParentDepartmentID := vars["departmentId"]
+		Limit, Offset := getLimitOffsetFromRequest(r, w)
+
+		Children := a.db.DepartmentChildrenList(ParentDepartmentID, Limit, Offset)
+
+		Success(w, r, http.StatusOK, Children, nil)
+	}
+}
+
+// handleCreateSubDepartment handles creating a nested sub-department under a parent department
+// @Summary Create Sub-Department
+// @Description Create a department nested under a parent department
+// @Tags organization
+// @Produce  json
+// @Param orgId path string true "the organization ID"
+// @Param departmentId path string true "the parent department ID"
+// @Param name body string true "the department name"
+// @Success 200 object standardJsonResponse{data=model.Department}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /organizations/{orgId}/departments/{departmentId}/departments [post]
+func (a *api) handleCreateSubDepartment() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.config.OrganizationsEnabled {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
+			return
+		}
+		vars := mux.Vars(r)
+		OrgID := vars["orgId"]
+		ParentDepartmentID := vars["departmentId"]
+		if !a.requireScope(w, r, "org:"+OrgID+":write") {
+			return
+		}
+		ParentRole := a.departmentRole(r, ParentDepartmentID)
+		if !a.requireAuthz(w, r, ParentRole, rbac.ActionCreate, rbac.ResourceDepartment) {
+			return
+		}
+		keyVal := getJSONRequestBody(r, w)
+
+		DepartmentName := keyVal["name"].(string)
+		NewDepartment, err := a.db.DepartmentCreateChild(OrgID, ParentDepartmentID, DepartmentName)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, NewDepartment, nil)
+	}
+}
+
+// handleGetDepartmentTree gets a department and its full nested sub-department tree
+// @Summary Get Department Tree
+// @Description Gets a department and all of its nested sub-departments
+// @Tags organization
+// @Produce  json
+// @Param orgId path string true "the organization ID"
+// @Param departmentId path string true "the department ID to get the tree for"
+// @Success 200 object standardJsonResponse{data=model.DepartmentTree}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /organizations/{orgId}/departments/{departmentId}/tree [get]
+func (a *api) handleGetDepartmentTree() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.config.OrganizationsEnabled {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
+			return
+		}
+		vars := mux.Vars(r)
+		DepartmentID := vars["departmentId"]
+
+		Tree, err := a.db.DepartmentTreeGet(DepartmentID)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, Tree, nil)
+	}
+}
+
 // handleGetDepartmentTeams gets a list of teams associated to the department
 // @Summary Get Department Teams
 // @Description Gets a list of organization department teams
@@ -231,10 +365,17 @@ func (a *api) handleDepartmentAddUser() http.HandlerFunc {
 			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
 			return
 		}
-		keyVal := getJSONRequestBody(r, w)
-
 		vars := mux.Vars(r)
 		DepartmentId := vars["departmentId"]
+		if !a.requireScope(w, r, "department:"+DepartmentId+":manage_users") {
+			return
+		}
+		DepartmentRole := a.departmentRole(r, DepartmentId)
+		if !a.requireAuthz(w, r, DepartmentRole, rbac.ActionManageUsers, rbac.ResourceDepartment) {
+			return
+		}
+		keyVal := getJSONRequestBody(r, w)
+
 		UserEmail := strings.ToLower(keyVal["email"].(string))
 		Role := keyVal["role"].(string)
 
@@ -306,12 +447,18 @@ func (a *api) handleDepartmentTeamAddUser() http.HandlerFunc {
 			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
 			return
 		}
-		keyVal := getJSONRequestBody(r, w)
-
 		vars := mux.Vars(r)
-		OrgID := vars["orgId"]
 		DepartmentID := vars["departmentId"]
 		TeamID := vars["teamId"]
+		if !a.requireScope(w, r, "team:"+TeamID+":manage_users") {
+			return
+		}
+		RequesterRole := a.departmentRole(r, DepartmentID)
+		if !a.requireAuthz(w, r, RequesterRole, rbac.ActionManageUsers, rbac.ResourceDepartment) {
+			return
+		}
+		keyVal := getJSONRequestBody(r, w)
+
 		UserEmail := strings.ToLower(keyVal["email"].(string))
 		Role := keyVal["role"].(string)
 
@@ -321,8 +468,8 @@ func (a *api) handleDepartmentTeamAddUser() http.HandlerFunc {
 			return
 		}
 
-		_, DepartmentRole, roleErr := a.db.DepartmentUserRole(User.Id, OrgID, DepartmentID)
-		if DepartmentRole == "" || roleErr != nil {
+		TargetRole, roleErr := a.db.DepartmentRoleInherited(User.Id, DepartmentID)
+		if roleErr != nil || TargetRole == "" {
 			Failure(w, r, http.StatusInternalServerError, Errorf(EUNAUTHORIZED, "DEPARTMENT_USER_REQUIRED"))
 			return
 		}
@@ -356,12 +503,12 @@ func (a *api) handleDepartmentTeamByUser() http.HandlerFunc {
 			return
 		}
 		OrgRole := r.Context().Value(contextKeyOrgRole).(string)
-		DepartmentRole := r.Context().Value(contextKeyDepartmentRole).(string)
 		TeamRole := r.Context().Value(contextKeyTeamRole).(string)
 		vars := mux.Vars(r)
 		OrgID := vars["orgId"]
 		DepartmentID := vars["departmentId"]
 		TeamID := vars["teamId"]
+		DepartmentRole := a.departmentRole(r, DepartmentID)
 
 		Organization, err := a.db.OrganizationGet(OrgID)
 		if err != nil {
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/auth/rbac"
+	"github.com/gorilla/mux"
+)
+
+// handleDepartmentInviteCreate handles creating a department invite link
+// @Summary Create Department Invite
+// @Description Create a tokenized invite link for joining a department (the invite is not tied to a team)
+// @Tags organization
+// @Produce  json
+// @Param orgId path string true "the organization ID"
+// @Param departmentId path string true "the department ID"
+// @Param role body string true "the role granted on accept" Enums(MEMBER, ADMIN)
+// @Success 200 object standardJsonResponse{data=model.Invite}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /organizations/{orgId}/departments/{departmentId}/invites [post]
+func (a *api) handleDepartmentInviteCreate() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.config.OrganizationsEnabled {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
+			return
+		}
+		vars := mux.Vars(r)
+		DepartmentID := vars["departmentId"]
+		if !a.requireScope(w, r, "department:"+DepartmentID+":manage_users") {
+			return
+		}
+		if !a.requireAuthz(w, r, a.departmentRole(r, DepartmentID), rbac.ActionManageUsers, rbac.ResourceDepartment) {
+			return
+		}
+		keyVal := getJSONRequestBody(r, w)
+
+		Role := keyVal["role"].(string)
+		MaxUses := 1
+		if maxUses, ok := keyVal["maxUses"].(float64); ok {
+			MaxUses = int(maxUses)
+		}
+
+		Invite, err := a.db.CreateDepartmentInvite(DepartmentID, Role, 7*24*time.Hour, MaxUses)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, Invite, nil)
+	}
+}
+
+// handleInviteGet handles getting the public details of an invite for the signup page
+// @Summary Get Invite
+// @Description Get the org/department/team names and role for an invite token
+// @Tags organization
+// @Produce  json
+// @Param token path string true "the invite token"
+// @Success 200 object standardJsonResponse{data=model.InviteDetails}
+// @Failure 404 object standardJsonResponse{}
+// @Router /invites/{token} [get]
+func (a *api) handleInviteGet() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		Token := vars["token"]
+
+		Details, err := a.db.GetDepartmentInvite(Token)
+		if err != nil {
+			Failure(w, r, http.StatusNotFound, Errorf(ENOTFOUND, "INVITE_NOT_FOUND"))
+			return
+		}
+
+		Success(w, r, http.StatusOK, Details, nil)
+	}
+}
+
+// handleInviteAccept handles accepting an invite, creating the user if they don't exist
+// @Summary Accept Invite
+// @Description Accept an invite, creating a verified user if none exists, then joining them up
+// @Tags organization
+// @Produce  json
+// @Param token path string true "the invite token"
+// @Param name body string true "the invitee's name"
+// @Param email body string true "the invitee's email"
+// @Param password body string true "the invitee's password"
+// @Success 200 object standardJsonResponse{data=model.User}
+// @Failure 500 object standardJsonResponse{}
+// @Router /invites/{token}/accept [post]
+func (a *api) handleInviteAccept() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		Token := vars["token"]
+		keyVal := getJSONRequestBody(r, w)
+
+		Name := keyVal["name"].(string)
+		Email := keyVal["email"].(string)
+		Password := keyVal["password"].(string)
+
+		User, err := a.db.AcceptDepartmentInvite(Token, Name, Email, Password)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, User, nil)
+	}
+}
+
+// handleDepartmentInviteRevoke handles revoking a department invite so it can no longer be redeemed
+// @Summary Revoke Invite
+// @Description Revoke a department invite
+// @Tags organization
+// @Produce  json
+// @Param orgId path string true "the organization ID"
+// @Param departmentId path string true "the department ID"
+// @Param inviteId path string true "the invite ID to revoke"
+// @Success 200 object standardJsonResponse{}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /organizations/{orgId}/departments/{departmentId}/invites/{inviteId} [delete]
+func (a *api) handleDepartmentInviteRevoke() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.config.OrganizationsEnabled {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
+			return
+		}
+		vars := mux.Vars(r)
+		DepartmentID := vars["departmentId"]
+		Token := vars["inviteId"]
+		if !a.requireScope(w, r, "department:"+DepartmentID+":manage_users") {
+			return
+		}
+		if !a.requireAuthz(w, r, a.departmentRole(r, DepartmentID), rbac.ActionManageUsers, rbac.ResourceDepartment) {
+			return
+		}
+
+		if err := a.db.RevokeDepartmentInvite(Token); err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, nil, nil)
+	}
+}
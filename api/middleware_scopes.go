@@ -0,0 +1,89 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"strings"
+)
+
+// contextKeyApiKeyScopes holds the scopes granted to the api key used to authenticate the request,
+// populated by ApiKeyScopeMiddleware; absent for session auth, an empty slice for an unscoped api key
+type contextKeyApiKeyScopesType struct{}
+
+var contextKeyApiKeyScopes = contextKeyApiKeyScopesType{}
+
+// apiKeyHeaderName is the HTTP header clients send their API key in
+const apiKeyHeaderName = "X-API-Key"
+
+// ApiKeyScopeMiddleware attaches the scopes granted to an incoming request's API key (if any)
+// to the request context so requireScope can enforce them. Requests authenticated by session
+// or by an unscoped/legacy key fall through with no scopes attached, which requireScope treats
+// as full access, matching pre-scope behavior.
+func (a *api) ApiKeyScopeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		APIKey := r.Header.Get(apiKeyHeaderName)
+		if APIKey == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		scopes, err := a.db.GetApiKeyScopesByRawKey(APIKey)
+		if err != nil {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(setApiKeyScopesContext(r.Context(), scopes)))
+	})
+}
+
+// scopeAllows returns true when scopes is empty (unscoped/legacy key, full access) or
+// contains a scope matching requiredScope, supporting a trailing wildcard e.g. "team:*"
+func scopeAllows(scopes []string, requiredScope string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+
+	for _, scope := range scopes {
+		if scope == requiredScope {
+			return true
+		}
+		if strings.HasSuffix(scope, ":*") && strings.HasPrefix(requiredScope, strings.TrimSuffix(scope, "*")) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// requireScope fails the request with EUNAUTHORIZED if the authenticating api key was scoped
+// and does not grant requiredScope. It prefers scopes already attached to the request context
+// by ApiKeyScopeMiddleware, but falls back to resolving them directly from the request's api
+// key header so the check is correct even on a route the middleware chain isn't mounted on.
+func (a *api) requireScope(w http.ResponseWriter, r *http.Request, requiredScope string) bool {
+	scopes, ok := r.Context().Value(contextKeyApiKeyScopes).([]string)
+	if !ok {
+		APIKey := r.Header.Get(apiKeyHeaderName)
+		if APIKey == "" {
+			return true
+		}
+
+		var err error
+		scopes, err = a.db.GetApiKeyScopesByRawKey(APIKey)
+		if err != nil {
+			return true
+		}
+	}
+
+	if !scopeAllows(scopes, requiredScope) {
+		Failure(w, r, http.StatusUnauthorized, Errorf(EUNAUTHORIZED, "API_KEY_SCOPE_REQUIRED"))
+		return false
+	}
+
+	return true
+}
+
+// setApiKeyScopesContext attaches the granted scopes to the request context for requireScope to consume
+func setApiKeyScopesContext(ctx context.Context, scopes []string) context.Context {
+	return context.WithValue(ctx, contextKeyApiKeyScopes, scopes)
+}
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/smtp"
+	"strings"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/auth/rbac"
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+	"github.com/gorilla/mux"
+)
+
+// handleOrganizationImport handles bootstrapping an org's departments, teams, and user
+// assignments from a single JSON or CSV payload (e.g. an HR export), rather than requiring
+// one handleCreateDepartment call plus many handleDepartmentAddUser/handleDepartmentTeamAddUser
+// round-trips to build the same structure
+// @Summary Import Organization Structure
+// @Description Bulk create departments, teams, and user assignments for an organization
+// @Tags organization
+// @Accept  json,text/csv
+// @Produce  json
+// @Param orgId path string true "the organization ID to import into"
+// @Success 200 object standardJsonResponse{data=model.OrgImportResult}
+// @Failure 400 object standardJsonResponse{}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /organizations/{orgId}/import [post]
+func (a *api) handleOrganizationImport() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if !a.config.OrganizationsEnabled {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "ORGANIZATIONS_DISABLED"))
+			return
+		}
+		vars := mux.Vars(r)
+		OrgID := vars["orgId"]
+		if !a.requireScope(w, r, "org:"+OrgID+":write") {
+			return
+		}
+		OrgRole := r.Context().Value(contextKeyOrgRole).(string)
+		if !a.requireAuthz(w, r, OrgRole, rbac.ActionManageUsers, rbac.ResourceOrganization) {
+			return
+		}
+
+		Spec, specErr := parseOrgImportSpec(r)
+		if specErr != nil {
+			Failure(w, r, http.StatusBadRequest, Errorf(EINVALID, "INVALID_IMPORT_PAYLOAD"))
+			return
+		}
+
+		Result, err := a.db.OrganizationImport(OrgID, *Spec)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		for i, row := range Result.Rows {
+			if !row.Success || row.Detail != "provisional account created and added" {
+				continue
+			}
+
+			if err := a.notifyImportedUser(row.Row); err != nil {
+				Result.Rows[i].Detail = "provisional account created and added; invite email not sent: " + err.Error()
+				continue
+			}
+			Result.Rows[i].Notified = true
+		}
+
+		Success(w, r, http.StatusOK, Result, nil)
+	}
+}
+
+// parseOrgImportSpec reads the request body as either JSON or CSV, based on Content-Type,
+// and builds the equivalent model.OrgImportSpec
+func parseOrgImportSpec(r *http.Request) (*model.OrgImportSpec, error) {
+	contentType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	if contentType == "text/csv" {
+		return parseOrgImportCSV(r.Body)
+	}
+
+	var Spec model.OrgImportSpec
+	if err := json.NewDecoder(r.Body).Decode(&Spec); err != nil {
+		return nil, err
+	}
+
+	return &Spec, nil
+}
+
+// parseOrgImportCSV expects rows of `department,team,email,role`, where team is blank for a
+// department-only (no team) assignment, and builds the equivalent nested model.OrgImportSpec
+func parseOrgImportCSV(body io.Reader) (*model.OrgImportSpec, error) {
+	reader := csv.NewReader(body)
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	departments := make(map[string]*model.OrgImportDepartment)
+	teams := make(map[string]*model.OrgImportTeam)
+	var departmentOrder []string
+	var teamOrder []string
+	teamDepartment := make(map[string]string)
+
+	for _, rec := range records {
+		if len(rec) != 4 {
+			continue
+		}
+		departmentName, teamName, email, role := rec[0], rec[1], rec[2], rec[3]
+
+		dept, ok := departments[departmentName]
+		if !ok {
+			dept = &model.OrgImportDepartment{Name: departmentName}
+			departments[departmentName] = dept
+			departmentOrder = append(departmentOrder, departmentName)
+		}
+
+		if teamName == "" {
+			dept.Users = append(dept.Users, model.OrgImportUser{Email: email, Role: role})
+			continue
+		}
+
+		teamKey := departmentName + "/" + teamName
+		team, ok := teams[teamKey]
+		if !ok {
+			team = &model.OrgImportTeam{Name: teamName}
+			teams[teamKey] = team
+			teamOrder = append(teamOrder, teamKey)
+			teamDepartment[teamKey] = departmentName
+		}
+		team.Users = append(team.Users, model.OrgImportUser{Email: email, Role: role})
+	}
+
+	for _, teamKey := range teamOrder {
+		dept := departments[teamDepartment[teamKey]]
+		dept.Teams = append(dept.Teams, *teams[teamKey])
+	}
+
+	Spec := &model.OrgImportSpec{}
+	for _, name := range departmentOrder {
+		Spec.Departments = append(Spec.Departments, *departments[name])
+	}
+
+	return Spec, nil
+}
+
+// notifyImportedUser emails a newly provisioned account a notice that it was created, sending
+// it with smtp.SendMail using the instance's SMTP settings; no invite token is created or
+// included in the message. row is formatted as
+// "department:{name}:user:{email}" or "team:{name}:user:{email}"
+func (a *api) notifyImportedUser(row string) error {
+	sep := strings.LastIndex(row, ":")
+	if sep < 0 {
+		return fmt.Errorf("malformed import notification row: %s", row)
+	}
+	email := row[sep+1:]
+
+	if a.config.SMTPHost == "" {
+		return errors.New("no mailer configured for import notifications")
+	}
+
+	subject := "You've been added on " + a.config.AppDomain
+	body := fmt.Sprintf("An account has been provisioned for you on %s. "+
+		"Visit %s to set your password and get started.", a.config.AppDomain, a.config.AppDomain)
+	msg := []byte("To: " + email + "\r\nSubject: " + subject + "\r\n\r\n" + body + "\r\n")
+
+	addr := fmt.Sprintf("%s:%d", a.config.SMTPHost, a.config.SMTPPort)
+	auth := smtp.PlainAuth("", a.config.SMTPUser, a.config.SMTPPass, a.config.SMTPHost)
+
+	return smtp.SendMail(addr, auth, a.config.SMTPSender, []string{email}, msg)
+}
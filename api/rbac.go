@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"sync"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/auth/rbac"
+	"github.com/StevenWeathers/thunderdome-planning-poker/db"
+	"github.com/gorilla/mux"
+)
+
+// authorizers caches one rbac.Authorizer per Database instance so its policy set is only
+// loaded once per process rather than once per request
+var (
+	authorizers   = make(map[*db.Database]*rbac.Authorizer)
+	authorizersMu sync.Mutex
+)
+
+// authz lazily builds (or returns the cached) Authorizer backed by a.db's policy table
+func (a *api) authz() (*rbac.Authorizer, error) {
+	authorizersMu.Lock()
+	defer authorizersMu.Unlock()
+
+	if az, ok := authorizers[a.db]; ok {
+		return az, nil
+	}
+
+	az, err := rbac.NewAuthorizer(a.db.RBACPolicyList)
+	if err != nil {
+		return nil, err
+	}
+
+	authorizers[a.db] = az
+	return az, nil
+}
+
+// requireAuthz fails the request with EUNAUTHORIZED unless subjectRole is granted action on
+// resource by the RBAC policy table, replacing the scattered ad-hoc role string checks
+// previously duplicated across the organization/department/team handlers
+func (a *api) requireAuthz(w http.ResponseWriter, r *http.Request, subjectRole string, action rbac.Action, resource rbac.Resource) bool {
+	az, err := a.authz()
+	if err != nil {
+		Failure(w, r, http.StatusInternalServerError, err)
+		return false
+	}
+
+	if err := az.Authorize(r.Context(), subjectRole, action, resource); err != nil {
+		Failure(w, r, http.StatusUnauthorized, Errorf(EUNAUTHORIZED, "UNAUTHORIZED"))
+		return false
+	}
+
+	return true
+}
+
+// handleRBACPolicyList gets the full set of RBAC policies
+// @Summary Get RBAC Policies
+// @Description Get the full set of role/resource/action authorization policies
+// @Tags admin
+// @Produce  json
+// @Success 200 object standardJsonResponse{data=[]model.RBACPolicy}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /admin/rbac/policies [get]
+func (a *api) handleRBACPolicyList() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		Policies, err := a.db.RBACPolicyList()
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		Success(w, r, http.StatusOK, Policies, nil)
+	}
+}
+
+// handleRBACPolicyUpsert handles creating or updating an RBAC policy
+// @Summary Upsert RBAC Policy
+// @Description Create or update the allow/deny decision for a role/resource/action tuple
+// @Tags admin
+// @Produce  json
+// @Param role body string true "the role this policy applies to"
+// @Param resource body string true "the resource this policy applies to"
+// @Param action body string true "the action this policy applies to"
+// @Param allow body bool true "whether the role is allowed to perform the action"
+// @Success 200 object standardJsonResponse{data=model.RBACPolicy}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /admin/rbac/policies [put]
+func (a *api) handleRBACPolicyUpsert() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		keyVal := getJSONRequestBody(r, w)
+
+		Role := keyVal["role"].(string)
+		Resource := keyVal["resource"].(string)
+		Action := keyVal["action"].(string)
+		Allow, _ := keyVal["allow"].(bool)
+
+		Policy, err := a.db.RBACPolicyUpsert(Role, Resource, Action, Allow)
+		if err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if az, azErr := a.authz(); azErr == nil {
+			_ = az.Refresh()
+		}
+
+		Success(w, r, http.StatusOK, Policy, nil)
+	}
+}
+
+// handleRBACPolicyDelete handles deleting an RBAC policy, reverting to the default deny
+// @Summary Delete RBAC Policy
+// @Description Delete an RBAC policy
+// @Tags admin
+// @Produce  json
+// @Param policyId path string true "the policy ID to delete"
+// @Success 200 object standardJsonResponse{}
+// @Failure 500 object standardJsonResponse{}
+// @Security ApiKeyAuth
+// @Router /admin/rbac/policies/{policyId} [delete]
+func (a *api) handleRBACPolicyDelete() http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		vars := mux.Vars(r)
+		PolicyID := vars["policyId"]
+
+		if err := a.db.RBACPolicyDelete(PolicyID); err != nil {
+			Failure(w, r, http.StatusInternalServerError, err)
+			return
+		}
+
+		if az, azErr := a.authz(); azErr == nil {
+			_ = az.Refresh()
+		}
+
+		Success(w, r, http.StatusOK, nil, nil)
+	}
+}
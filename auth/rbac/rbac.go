@@ -0,0 +1,93 @@
+// Package rbac centralizes authorization decisions that were previously scattered as ad-hoc
+// role string comparisons across the organization/department/team handlers, inspired by
+// Coder's authz layer. It is deliberately storage-agnostic: callers supply a PolicyLoader
+// (typically backed by Database.RBACPolicyList) and the Authorizer caches the result in
+// memory so Authorize doesn't round-trip to Postgres on every request.
+package rbac
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+)
+
+// Resource identifies the kind of object an action is performed against
+type Resource string
+
+// Action identifies what a subject is attempting to do to a Resource
+type Action string
+
+const (
+	ResourceOrganization Resource = "organization"
+	ResourceDepartment   Resource = "department"
+	ResourceTeam         Resource = "team"
+	ResourceBattle       Resource = "battle"
+	ResourceApiKey       Resource = "apikey"
+)
+
+const (
+	ActionCreate      Action = "create"
+	ActionRead        Action = "read"
+	ActionUpdate      Action = "update"
+	ActionDelete      Action = "delete"
+	ActionManageUsers Action = "manage_users"
+)
+
+// ErrUnauthorized is returned when no policy grants the requested action to the subject's role
+var ErrUnauthorized = errors.New("unauthorized")
+
+// PolicyLoader loads the current set of policies, e.g. from Postgres
+type PolicyLoader func() ([]model.RBACPolicy, error)
+
+// Authorizer evaluates whether a subject's role may perform an action on a resource, backed
+// by a policy set that is loaded once and cached in memory until Refresh is called
+type Authorizer struct {
+	mu       sync.RWMutex
+	policies []model.RBACPolicy
+	load     PolicyLoader
+}
+
+// NewAuthorizer builds an Authorizer and performs its initial policy load
+func NewAuthorizer(load PolicyLoader) (*Authorizer, error) {
+	a := &Authorizer{load: load}
+	if err := a.Refresh(); err != nil {
+		return nil, err
+	}
+
+	return a, nil
+}
+
+// Refresh reloads the cached policy set from the PolicyLoader
+func (a *Authorizer) Refresh() error {
+	policies, err := a.load()
+	if err != nil {
+		return err
+	}
+
+	a.mu.Lock()
+	a.policies = policies
+	a.mu.Unlock()
+
+	return nil
+}
+
+// Authorize returns nil if subjectRole is granted action on resource, otherwise ErrUnauthorized.
+// The context is accepted but not used; the decision depends only on subjectRole, action and
+// resource.
+func (a *Authorizer) Authorize(_ context.Context, subjectRole string, action Action, resource Resource) error {
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+
+	for _, p := range a.policies {
+		if p.Role == subjectRole && p.Resource == string(resource) && p.Action == string(action) {
+			if p.Allow {
+				return nil
+			}
+			return ErrUnauthorized
+		}
+	}
+
+	return ErrUnauthorized
+}
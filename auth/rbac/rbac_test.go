@@ -0,0 +1,113 @@
+package rbac
+
+import (
+	"context"
+	"testing"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+)
+
+// seedPolicies mirrors the rows inserted by db/migrations/0005_rbac_policies.sql, so this
+// suite exercises the same allow/deny matrix every department/organization/team route is
+// actually gated by at runtime, rather than a policy set invented for the test.
+func seedPolicies() []model.RBACPolicy {
+	return []model.RBACPolicy{
+		{Role: "ADMIN", Resource: string(ResourceDepartment), Action: string(ActionManageUsers), Allow: true},
+		{Role: "MEMBER", Resource: string(ResourceDepartment), Action: string(ActionManageUsers), Allow: true},
+		{Role: "ADMIN", Resource: string(ResourceDepartment), Action: string(ActionCreate), Allow: true},
+		{Role: "ADMIN", Resource: string(ResourceOrganization), Action: string(ActionManageUsers), Allow: true},
+		{Role: "ADMIN", Resource: string(ResourceTeam), Action: string(ActionManageUsers), Allow: true},
+	}
+}
+
+func newTestAuthorizer(t *testing.T, policies []model.RBACPolicy) *Authorizer {
+	t.Helper()
+	a, err := NewAuthorizer(func() ([]model.RBACPolicy, error) {
+		return policies, nil
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer returned error: %v", err)
+	}
+	return a
+}
+
+// TestAuthorize_SeededPolicyMatrix runs every role against every resource/action combination
+// a department/organization/team route can request, asserting allow/deny matches the seeded
+// migration exactly -- so a missing seed row (the department:create regression this guards
+// against) or an accidental broadening of a grant fails the suite instead of surfacing as a
+// guaranteed 401 (or an unintended 200) in production.
+func TestAuthorize_SeededPolicyMatrix(t *testing.T) {
+	a := newTestAuthorizer(t, seedPolicies())
+
+	roles := []string{"ADMIN", "MEMBER", "GUEST", ""}
+	resources := []Resource{ResourceOrganization, ResourceDepartment, ResourceTeam, ResourceBattle, ResourceApiKey}
+	actions := []Action{ActionCreate, ActionRead, ActionUpdate, ActionDelete, ActionManageUsers}
+
+	allowed := map[[3]string]bool{}
+	for _, p := range seedPolicies() {
+		allowed[[3]string{p.Role, p.Resource, p.Action}] = p.Allow
+	}
+
+	for _, role := range roles {
+		for _, resource := range resources {
+			for _, action := range actions {
+				role, resource, action := role, resource, action
+				t.Run(role+"/"+string(resource)+"/"+string(action), func(t *testing.T) {
+					err := a.Authorize(context.Background(), role, action, resource)
+
+					wantAllow := allowed[[3]string{role, string(resource), string(action)}]
+					gotAllow := err == nil
+
+					if gotAllow != wantAllow {
+						t.Errorf("Authorize(%q, %q, %q) allowed=%v, want %v", role, action, resource, gotAllow, wantAllow)
+					}
+					if !gotAllow && err != ErrUnauthorized {
+						t.Errorf("Authorize(%q, %q, %q) returned error %v, want ErrUnauthorized", role, action, resource, err)
+					}
+				})
+			}
+		}
+	}
+}
+
+// TestAuthorize_ExplicitDenyOverridesDefault covers a policy row with allow=false, which should
+// still resolve to ErrUnauthorized just like an absent row, but via the explicit-deny branch
+// rather than the default-deny fallthrough.
+func TestAuthorize_ExplicitDenyOverridesDefault(t *testing.T) {
+	a := newTestAuthorizer(t, []model.RBACPolicy{
+		{Role: "MEMBER", Resource: string(ResourceOrganization), Action: string(ActionDelete), Allow: false},
+	})
+
+	err := a.Authorize(context.Background(), "MEMBER", ActionDelete, ResourceOrganization)
+	if err != ErrUnauthorized {
+		t.Errorf("Authorize with explicit deny row = %v, want ErrUnauthorized", err)
+	}
+}
+
+// TestAuthorize_RefreshPicksUpNewPolicies ensures a Refresh call (e.g. after an admin edits
+// policies via the /admin/rbac/policies endpoints) is reflected by subsequent Authorize calls
+// without needing to rebuild the Authorizer.
+func TestAuthorize_RefreshPicksUpNewPolicies(t *testing.T) {
+	policies := []model.RBACPolicy{}
+	a, err := NewAuthorizer(func() ([]model.RBACPolicy, error) {
+		return policies, nil
+	})
+	if err != nil {
+		t.Fatalf("NewAuthorizer returned error: %v", err)
+	}
+
+	if err := a.Authorize(context.Background(), "ADMIN", ActionRead, ResourceBattle); err != ErrUnauthorized {
+		t.Fatalf("Authorize before grant = %v, want ErrUnauthorized", err)
+	}
+
+	policies = []model.RBACPolicy{
+		{Role: "ADMIN", Resource: string(ResourceBattle), Action: string(ActionRead), Allow: true},
+	}
+	if err := a.Refresh(); err != nil {
+		t.Fatalf("Refresh returned error: %v", err)
+	}
+
+	if err := a.Authorize(context.Background(), "ADMIN", ActionRead, ResourceBattle); err != nil {
+		t.Fatalf("Authorize after grant = %v, want nil", err)
+	}
+}
@@ -4,11 +4,16 @@ import (
 	"errors"
 	"log"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/StevenWeathers/thunderdome-planning-poker/model"
 )
 
+// apiKeyRotationGracePeriod is how long a rotated key keeps authenticating
+// after a replacement is issued, so in-flight clients have time to pick up the new secret
+const apiKeyRotationGracePeriod = 24 * time.Hour
+
 // GenerateApiKey generates a new API key for a User
 func (d *Database) GenerateApiKey(UserID string, KeyName string) (*model.APIKey, error) {
 	apiPrefix, prefixErr := randomString(8)
@@ -37,6 +42,7 @@ func (d *Database) GenerateApiKey(UserID string, KeyName string) (*model.APIKey,
 	}
 	hashedKey := hashString(APIKEY.Key)
 	keyID := apiPrefix + "." + hashedKey
+	APIKEY.Id = keyID
 
 	e := d.db.QueryRow(
 		`SELECT createdDate FROM user_apikey_add($1, $2, $3);`,
@@ -52,11 +58,163 @@ func (d *Database) GenerateApiKey(UserID string, KeyName string) (*model.APIKey,
 	return APIKEY, nil
 }
 
+// GenerateApiKeyWithTTL generates a new API key for a User that automatically expires after ttl
+func (d *Database) GenerateApiKeyWithTTL(UserID string, KeyName string, ttl time.Duration) (*model.APIKey, error) {
+	APIKEY, err := d.GenerateApiKey(UserID, KeyName)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(ttl)
+	if _, err := d.db.Exec(
+		`UPDATE api_keys SET expires_date = $1 WHERE id = $2;`,
+		expiresAt, APIKEY.Id,
+	); err != nil {
+		log.Println(err)
+		return nil, errors.New("unable to set api key expiration")
+	}
+
+	APIKEY.ExpiresAt = &expiresAt
+
+	return APIKEY, nil
+}
+
+// RotateApiKey issues a new secret sharing the same prefix as KeyID and marks the old key
+// revoked, keeping it valid for a grace period so in-flight clients aren't broken immediately
+func (d *Database) RotateApiKey(UserID string, KeyID string) (*model.APIKey, error) {
+	splitKey := strings.Split(KeyID, ".")
+	if len(splitKey) != 2 {
+		return nil, errors.New("invalid api key id")
+	}
+	apiPrefix := splitKey[0]
+
+	apiSecret, secretErr := randomString(32)
+	if secretErr != nil {
+		log.Println(secretErr)
+		return nil, errors.New("error generating api secret")
+	}
+
+	NewKey := &model.APIKey{
+		Name:        d.KeyName(KeyID),
+		Key:         apiPrefix + "." + apiSecret,
+		UserId:      UserID,
+		Prefix:      apiPrefix,
+		Active:      true,
+		CreatedDate: time.Now(),
+	}
+	newKeyID := apiPrefix + "." + hashString(NewKey.Key)
+	NewKey.Id = newKeyID
+
+	e := d.db.QueryRow(
+		`SELECT createdDate FROM user_apikey_add($1, $2, $3);`,
+		newKeyID,
+		NewKey.Name,
+		UserID,
+	).Scan(&NewKey.CreatedDate)
+	if e != nil {
+		log.Println(e)
+		return nil, errors.New("unable to create rotated api key")
+	}
+
+	revokedAt := time.Now()
+	graceExpiry := revokedAt.Add(apiKeyRotationGracePeriod)
+	if _, err := d.db.Exec(
+		`UPDATE api_keys SET revoked_date = $1, expires_date = $2 WHERE id = $3 AND user_id = $4;`,
+		revokedAt, graceExpiry, KeyID, UserID,
+	); err != nil {
+		log.Println(err)
+		return nil, errors.New("unable to revoke previous api key")
+	}
+
+	return NewKey, nil
+}
+
+// KeyName looks up the name of an existing api key by its id, used when rotating a key
+// so the replacement carries over the same display name
+func (d *Database) KeyName(KeyID string) string {
+	var name string
+	if err := d.db.QueryRow(`SELECT name FROM api_keys WHERE id = $1;`, KeyID).Scan(&name); err != nil {
+		log.Println(err)
+		return ""
+	}
+
+	return name
+}
+
+// GenerateApiKeyWithScopes generates a new scoped API key for a User, restricting what
+// the key can be used for (e.g. "battle:read", "team:{teamId}:admin", "org:{orgId}:read")
+func (d *Database) GenerateApiKeyWithScopes(UserID string, KeyName string, Scopes []string) (*model.APIKey, error) {
+	APIKEY, err := d.GenerateApiKey(UserID, KeyName)
+	if err != nil {
+		return nil, err
+	}
+
+	if scopeErr := d.apiKeyScopesAdd(APIKEY.Id, Scopes); scopeErr != nil {
+		log.Println(scopeErr)
+		return nil, errors.New("unable to assign scopes to new api key")
+	}
+
+	APIKEY.Scopes = Scopes
+
+	return APIKEY, nil
+}
+
+// apiKeyScopesAdd persists the scopes granted to an api key, keyed by the hashed key id
+func (d *Database) apiKeyScopesAdd(KeyID string, Scopes []string) error {
+	for _, scope := range Scopes {
+		if _, err := d.db.Exec(
+			`INSERT INTO api_key_scopes (api_key_id, scope) VALUES ($1, $2);`,
+			KeyID, scope,
+		); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// GetApiKeyScopes gets the scopes granted to an api key
+func (d *Database) GetApiKeyScopes(KeyID string) ([]string, error) {
+	var Scopes = make([]string, 0)
+	rows, err := d.db.Query(
+		`SELECT scope FROM api_key_scopes WHERE api_key_id = $1 ORDER BY scope;`,
+		KeyID,
+	)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var scope string
+		if err := rows.Scan(&scope); err != nil {
+			log.Println(err)
+			continue
+		}
+		Scopes = append(Scopes, scope)
+	}
+
+	return Scopes, nil
+}
+
+// GetApiKeyScopesByRawKey gets the scopes granted to an api key, given the raw `prefix.secret`
+// value a client sends, hashing it the same way GetApiKeyUser does before looking it up since
+// api_key_scopes (like api_keys) is keyed by the hashed `prefix.hash` id, not the raw key
+func (d *Database) GetApiKeyScopesByRawKey(APIKey string) ([]string, error) {
+	splitKey := strings.Split(APIKey, ".")
+	if len(splitKey) != 2 {
+		return nil, errors.New("invalid api key")
+	}
+	keyID := splitKey[0] + "." + hashString(APIKey)
+
+	return d.GetApiKeyScopes(keyID)
+}
+
 // GetUserApiKeys gets a list of api keys for a user
 func (d *Database) GetUserApiKeys(UserID string) ([]*model.APIKey, error) {
 	var APIKeys = make([]*model.APIKey, 0)
 	rows, err := d.db.Query(
-		"SELECT id, name, user_id, active, created_date, updated_date FROM api_keys WHERE user_id = $1 ORDER BY created_date",
+		"SELECT id, name, user_id, active, created_date, updated_date, expires_date, last_used_date, revoked_date FROM api_keys WHERE user_id = $1 ORDER BY created_date",
 		UserID,
 	)
 	if err == nil {
@@ -72,6 +230,9 @@ func (d *Database) GetUserApiKeys(UserID string) ([]*model.APIKey, error) {
 				&ak.Active,
 				&ak.CreatedDate,
 				&ak.UpdatedDate,
+				&ak.ExpiresAt,
+				&ak.LastUsedAt,
+				&ak.RevokedAt,
 			); err != nil {
 				log.Println(err)
 			} else {
@@ -133,6 +294,7 @@ func (d *Database) GetApiKeyUser(APK string) (*model.User, error) {
 		FROM api_keys ak
 		LEFT JOIN users u ON u.id = ak.user_id
 		WHERE ak.id = $1 AND ak.active = true
+			AND (ak.expires_date IS NULL OR ak.expires_date > NOW())
 `,
 		keyID,
 	).Scan(
@@ -156,6 +318,35 @@ func (d *Database) GetApiKeyUser(APK string) (*model.User, error) {
 	}
 
 	User.GravatarHash = createGravatarHash(User.Email)
+	d.queueApiKeyLastUsedUpdate(keyID)
 
 	return User, nil
 }
+
+// apiKeyLastUsedCh buffers LastUsedAt updates so authenticated requests aren't slowed
+// down by a write on every single request; a single background worker drains it
+var (
+	apiKeyLastUsedCh   chan string
+	apiKeyLastUsedOnce sync.Once
+)
+
+// queueApiKeyLastUsedUpdate enqueues a best-effort async LastUsedAt update for keyID,
+// starting the background worker on first use
+func (d *Database) queueApiKeyLastUsedUpdate(keyID string) {
+	apiKeyLastUsedOnce.Do(func() {
+		apiKeyLastUsedCh = make(chan string, 256)
+		go func() {
+			for id := range apiKeyLastUsedCh {
+				if _, err := d.db.Exec(`UPDATE api_keys SET last_used_date = NOW() WHERE id = $1;`, id); err != nil {
+					log.Println(err)
+				}
+			}
+		}()
+	})
+
+	select {
+	case apiKeyLastUsedCh <- keyID:
+	default:
+		log.Println("api key last_used update channel full, dropping update for", keyID)
+	}
+}
@@ -0,0 +1,171 @@
+package db
+
+import (
+	"errors"
+	"log"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+)
+
+// DepartmentCreateChild creates a sub-department nested under ParentDepartmentID
+func (d *Database) DepartmentCreateChild(OrgID string, ParentDepartmentID string, DepartmentName string) (*model.Department, error) {
+	Department := &model.Department{
+		Name:               DepartmentName,
+		OrganizationId:     OrgID,
+		ParentDepartmentId: ParentDepartmentID,
+	}
+
+	e := d.db.QueryRow(
+		`INSERT INTO department (name, organization_id, parent_department_id)
+		VALUES ($1, $2, $3) RETURNING id, created_date, updated_date;`,
+		DepartmentName, OrgID, ParentDepartmentID,
+	).Scan(&Department.Id, &Department.CreatedDate, &Department.UpdatedDate)
+	if e != nil {
+		log.Println(e)
+		return nil, e
+	}
+
+	return Department, nil
+}
+
+// DepartmentChildrenList gets a list of the direct sub-departments of a department
+func (d *Database) DepartmentChildrenList(ParentDepartmentID string, Limit int, Offset int) []*model.Department {
+	var departments = make([]*model.Department, 0)
+
+	rows, err := d.db.Query(
+		`SELECT id, name, organization_id, COALESCE(parent_department_id::TEXT, ''), created_date, updated_date
+		FROM department WHERE parent_department_id = $1
+		ORDER BY name LIMIT $2 OFFSET $3;`,
+		ParentDepartmentID, Limit, Offset,
+	)
+	if err != nil {
+		log.Println(err)
+		return departments
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var dept model.Department
+		if err := rows.Scan(
+			&dept.Id,
+			&dept.Name,
+			&dept.OrganizationId,
+			&dept.ParentDepartmentId,
+			&dept.CreatedDate,
+			&dept.UpdatedDate,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		departments = append(departments, &dept)
+	}
+
+	return departments
+}
+
+// DepartmentTreeGet builds the full nested tree of sub-departments rooted at DepartmentID
+// using a recursive CTE so arbitrarily deep hierarchies are fetched in a single query
+func (d *Database) DepartmentTreeGet(DepartmentID string) (*model.DepartmentTree, error) {
+	rows, err := d.db.Query(`
+		WITH RECURSIVE department_tree AS (
+			SELECT id, name, organization_id, parent_department_id, created_date, updated_date
+			FROM department WHERE id = $1
+			UNION ALL
+			SELECT dep.id, dep.name, dep.organization_id, dep.parent_department_id, dep.created_date, dep.updated_date
+			FROM department dep
+			INNER JOIN department_tree dt ON dep.parent_department_id = dt.id
+		)
+		SELECT id, name, organization_id, COALESCE(parent_department_id::TEXT, ''), created_date, updated_date
+		FROM department_tree;`,
+		DepartmentID,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	nodes := make(map[string]*model.DepartmentTree)
+	var order []string
+
+	for rows.Next() {
+		var dept model.Department
+		if err := rows.Scan(
+			&dept.Id,
+			&dept.Name,
+			&dept.OrganizationId,
+			&dept.ParentDepartmentId,
+			&dept.CreatedDate,
+			&dept.UpdatedDate,
+		); err != nil {
+			log.Println(err)
+			continue
+		}
+		nodes[dept.Id] = &model.DepartmentTree{Department: dept, Children: make([]*model.DepartmentTree, 0)}
+		order = append(order, dept.Id)
+	}
+
+	root, ok := nodes[DepartmentID]
+	if !ok {
+		return nil, errors.New("department not found")
+	}
+
+	for _, id := range order {
+		if id == DepartmentID {
+			continue
+		}
+		node := nodes[id]
+		if parent, ok := nodes[node.ParentDepartmentId]; ok {
+			parent.Children = append(parent.Children, node)
+		}
+	}
+
+	return root, nil
+}
+
+// DepartmentRoleInherited resolves a user's effective department role by walking up the
+// department hierarchy, so an ADMIN on a parent department is treated as ADMIN of all
+// children, mirroring the existing org > department > team inheritance. It returns "" with
+// no error if the user has no role anywhere in the ancestry. Org-level role propagation (an
+// org ADMIN being treated as a department ADMIN too) happens one layer up, in the api package's
+// departmentRole helper, which already has the requester's org role resolved onto the request
+// context and so doesn't need this to re-fetch it.
+func (d *Database) DepartmentRoleInherited(UserID string, DepartmentID string) (string, error) {
+	rows, err := d.db.Query(`
+		WITH RECURSIVE department_ancestry AS (
+			SELECT id, parent_department_id, 0 AS depth
+			FROM department WHERE id = $1
+			UNION ALL
+			SELECT dep.id, dep.parent_department_id, da.depth + 1
+			FROM department dep
+			INNER JOIN department_ancestry da ON dep.id = da.parent_department_id
+		)
+		SELECT da.id, COALESCE(du.role, '')
+		FROM department_ancestry da
+		LEFT JOIN department_user du ON du.department_id = da.id AND du.user_id = $2
+		ORDER BY da.depth;`,
+		DepartmentID, UserID,
+	)
+	if err != nil {
+		log.Println(err)
+		return "", err
+	}
+	defer rows.Close()
+
+	nearestRole := ""
+	for rows.Next() {
+		var id, role string
+		if err := rows.Scan(&id, &role); err != nil {
+			log.Println(err)
+			continue
+		}
+		if role == "ADMIN" {
+			return role, nil
+		}
+		if nearestRole == "" && role != "" {
+			nearestRole = role
+		}
+	}
+
+	return nearestRole, nil
+}
@@ -0,0 +1,180 @@
+package db
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// CompareUserPassword verifies Password against UserID's stored password hash, returning an
+// error if they don't match (including when the account has no password set, e.g. SSO-only)
+func (d *Database) CompareUserPassword(UserID string, Password string) error {
+	var hashedPassword string
+	if err := d.db.QueryRow(`SELECT password FROM users WHERE id = $1;`, UserID).Scan(&hashedPassword); err != nil {
+		return err
+	}
+	if hashedPassword == "" {
+		return errors.New("account has no password set")
+	}
+
+	return bcrypt.CompareHashAndPassword([]byte(hashedPassword), []byte(Password))
+}
+
+// CreateDepartmentInvite creates a random invite token for a department, stored hashed, that
+// can be redeemed until ttl from now, up to maxUses times. The invite is not tied to a team
+// (its team_id is left unset). It can be redeemed by someone without an account, or by an
+// existing account whose password is supplied (see AcceptDepartmentInvite)
+func (d *Database) CreateDepartmentInvite(DepartmentID string, Role string, ttl time.Duration, MaxUses int) (*model.Invite, error) {
+	token, tokenErr := randomString(32)
+	if tokenErr != nil {
+		log.Println(tokenErr)
+		return nil, errors.New("error generating invite token")
+	}
+	hashedToken := hashString(token)
+
+	Invite := &model.Invite{
+		Id:           hashedToken,
+		DepartmentId: DepartmentID,
+		Role:         Role,
+		MaxUses:      MaxUses,
+		ExpiresAt:    time.Now().Add(ttl),
+	}
+
+	e := d.db.QueryRow(
+		`INSERT INTO team_invites (id, department_id, role, max_uses, expires_date)
+		VALUES ($1, $2, $3, $4, $5) RETURNING created_date;`,
+		Invite.Id, DepartmentID, Role, MaxUses, Invite.ExpiresAt,
+	).Scan(&Invite.CreatedDate)
+	if e != nil {
+		log.Println(e)
+		return nil, errors.New("unable to create department invite")
+	}
+
+	// the raw, unhashed token is what's embedded in the invite link -- it is never stored
+	Invite.Id = token
+
+	return Invite, nil
+}
+
+// GetDepartmentInvite gets the public details of an unexpired, unrevoked invite for display
+// on the signup page, without requiring the caller to be authenticated
+func (d *Database) GetDepartmentInvite(Token string) (*model.InviteDetails, error) {
+	hashedToken := hashString(Token)
+	Details := &model.InviteDetails{}
+
+	e := d.db.QueryRow(`
+		SELECT o.name, dep.name, COALESCE(t.name, ''), ti.role
+		FROM team_invites ti
+		INNER JOIN department dep ON dep.id = ti.department_id
+		INNER JOIN organization o ON o.id = dep.organization_id
+		LEFT JOIN team t ON t.id = ti.team_id
+		WHERE ti.id = $1 AND ti.use_count < ti.max_uses
+			AND ti.expires_date > NOW() AND ti.revoked_date IS NULL;`,
+		hashedToken,
+	).Scan(&Details.OrganizationName, &Details.DepartmentName, &Details.TeamName, &Details.Role)
+	if e != nil {
+		log.Println(e)
+		return nil, errors.New("invite not found or expired")
+	}
+
+	return Details, nil
+}
+
+// AcceptDepartmentInvite redeems an invite token, creating a verified user if one doesn't
+// already exist for Email, then adding them to the invite's department (and team, if set)
+func (d *Database) AcceptDepartmentInvite(Token string, Name string, Email string, Password string) (*model.User, error) {
+	hashedToken := hashString(Token)
+
+	var DepartmentID, TeamID, Role string
+	e := d.db.QueryRow(`
+		SELECT department_id, COALESCE(team_id::TEXT, ''), role
+		FROM team_invites
+		WHERE id = $1 AND use_count < max_uses AND expires_date > NOW() AND revoked_date IS NULL;`,
+		hashedToken,
+	).Scan(&DepartmentID, &TeamID, &Role)
+	if e != nil {
+		log.Println(e)
+		return nil, errors.New("invite not found or expired")
+	}
+
+	User, UserErr := d.GetUserByEmail(Email)
+	if UserErr == nil {
+		// an invite link is unauthenticated and publicly readable, so linking an existing
+		// account requires proving ownership of it the same way logging in would -- otherwise
+		// anyone holding the link could enroll an arbitrary known email into the department
+		if pwErr := d.CompareUserPassword(User.Id, Password); pwErr != nil {
+			return nil, errors.New("an account with this email already exists; please log in to accept this invite")
+		}
+	} else {
+		var createErr error
+		User, _, createErr = d.CreateUserRegistered(Name, Email, Password, "")
+		if createErr != nil {
+			log.Println(createErr)
+			return nil, errors.New("unable to create invited user")
+		}
+	}
+
+	if _, err := d.DepartmentAddUser(DepartmentID, User.Id, Role); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	if TeamID != "" {
+		if _, err := d.TeamAddUser(TeamID, User.Id, Role); err != nil {
+			log.Println(err)
+			return nil, err
+		}
+	}
+
+	if _, err := d.db.Exec(`UPDATE team_invites SET use_count = use_count + 1 WHERE id = $1;`, hashedToken); err != nil {
+		log.Println(err)
+	}
+
+	return User, nil
+}
+
+// RevokeDepartmentInvite revokes an invite so it can no longer be redeemed. Token is the raw
+// token value (the only form an admin ever holds); team_invites.id stores its hash, the same
+// as CreateDepartmentInvite, GetDepartmentInvite, and AcceptDepartmentInvite all key off of.
+func (d *Database) RevokeDepartmentInvite(Token string) error {
+	hashedToken := hashString(Token)
+
+	if _, err := d.db.Exec(`UPDATE team_invites SET revoked_date = NOW() WHERE id = $1;`, hashedToken); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// PurgeExpiredDepartmentInvites deletes invites that expired more than a day ago
+func (d *Database) PurgeExpiredDepartmentInvites() error {
+	if _, err := d.db.Exec(`DELETE FROM team_invites WHERE expires_date < NOW() - INTERVAL '1 day';`); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
+
+// StartExpiredInviteSweeper runs PurgeExpiredDepartmentInvites on interval until stopped,
+// intended to be launched once from server startup alongside the instance's other background jobs
+func (d *Database) StartExpiredInviteSweeper(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	go func() {
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := d.PurgeExpiredDepartmentInvites(); err != nil {
+					log.Println(err)
+				}
+			case <-done:
+				return
+			}
+		}
+	}()
+}
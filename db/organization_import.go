@@ -0,0 +1,203 @@
+package db
+
+import (
+	"database/sql"
+	"fmt"
+	"log"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+)
+
+// OrganizationImport bootstraps an entire org structure (departments, teams, and user
+// assignments) from a single spec, running inside one transaction that is committed once
+// every row has been attempted; rows that failed are left out of what is committed (along with
+// the teams and users under a failed department). Per-row outcomes are reported individually
+// (rather than aborting on the first error) so one bad email in a large HR export doesn't
+// block the rest of the import -- each row runs under its own SAVEPOINT, since a plain failed
+// statement would otherwise abort the whole transaction and take every later row down with it.
+func (d *Database) OrganizationImport(OrgID string, Spec model.OrgImportSpec) (*model.OrgImportResult, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer tx.Rollback()
+
+	imp := &orgImporter{db: d, tx: tx}
+	Result := &model.OrgImportResult{}
+
+	for _, dept := range Spec.Departments {
+		imp.department(OrgID, dept, Result)
+	}
+
+	if err := tx.Commit(); err != nil {
+		log.Println(err)
+		return nil, err
+	}
+
+	return Result, nil
+}
+
+// orgImporter threads the import transaction and a savepoint counter through the per-row
+// helpers, so each row can be rolled back to its own SAVEPOINT without poisoning the rest
+// of the import
+type orgImporter struct {
+	db           *Database
+	tx           *sql.Tx
+	savepointSeq int
+}
+
+// withSavepoint runs fn inside a fresh SAVEPOINT, rolling back to it (and only it) on error so
+// the surrounding transaction stays usable for the rows that follow
+func (imp *orgImporter) withSavepoint(fn func() error) error {
+	imp.savepointSeq++
+	name := fmt.Sprintf("org_import_%d", imp.savepointSeq)
+
+	if _, err := imp.tx.Exec(fmt.Sprintf("SAVEPOINT %s", name)); err != nil {
+		return err
+	}
+
+	if err := fn(); err != nil {
+		if _, rbErr := imp.tx.Exec(fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", name)); rbErr != nil {
+			log.Println(rbErr)
+		}
+		return err
+	}
+
+	if _, err := imp.tx.Exec(fmt.Sprintf("RELEASE SAVEPOINT %s", name)); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (imp *orgImporter) department(OrgID string, dept model.OrgImportDepartment, Result *model.OrgImportResult) {
+	row := fmt.Sprintf("department:%s", dept.Name)
+
+	var DepartmentID string
+	err := imp.withSavepoint(func() error {
+		return imp.tx.QueryRow(
+			`INSERT INTO department (name, organization_id) VALUES ($1, $2) RETURNING id;`,
+			dept.Name, OrgID,
+		).Scan(&DepartmentID)
+	})
+	if err != nil {
+		Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: false, Detail: err.Error()})
+		return
+	}
+	Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: true})
+
+	for _, u := range dept.Users {
+		imp.departmentUser(DepartmentID, dept.Name, u, Result)
+	}
+
+	for _, team := range dept.Teams {
+		imp.team(DepartmentID, dept.Name, team, Result)
+	}
+}
+
+func (imp *orgImporter) departmentUser(DepartmentID string, DepartmentName string, u model.OrgImportUser, Result *model.OrgImportResult) {
+	row := fmt.Sprintf("department:%s:user:%s", DepartmentName, u.Email)
+
+	var Provisioned bool
+	err := imp.withSavepoint(func() error {
+		UserID, provisioned, uErr := imp.resolveUser(u.Email)
+		if uErr != nil {
+			return uErr
+		}
+		Provisioned = provisioned
+
+		_, err := imp.tx.Exec(
+			`INSERT INTO department_user (department_id, user_id, role) VALUES ($1, $2, $3);`,
+			DepartmentID, UserID, u.Role,
+		)
+		return err
+	})
+	if err != nil {
+		Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: false, Detail: err.Error()})
+		return
+	}
+
+	detail := "added"
+	if Provisioned {
+		detail = "provisional account created and added"
+	}
+	Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: true, Detail: detail})
+}
+
+func (imp *orgImporter) team(DepartmentID string, DepartmentName string, team model.OrgImportTeam, Result *model.OrgImportResult) {
+	row := fmt.Sprintf("department:%s:team:%s", DepartmentName, team.Name)
+
+	var TeamID string
+	err := imp.withSavepoint(func() error {
+		return imp.tx.QueryRow(
+			`INSERT INTO team (name, department_id) VALUES ($1, $2) RETURNING id;`,
+			team.Name, DepartmentID,
+		).Scan(&TeamID)
+	})
+	if err != nil {
+		Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: false, Detail: err.Error()})
+		return
+	}
+	Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: true})
+
+	for _, u := range team.Users {
+		imp.teamUser(TeamID, team.Name, u, Result)
+	}
+}
+
+func (imp *orgImporter) teamUser(TeamID string, TeamName string, u model.OrgImportUser, Result *model.OrgImportResult) {
+	row := fmt.Sprintf("team:%s:user:%s", TeamName, u.Email)
+
+	var Provisioned bool
+	err := imp.withSavepoint(func() error {
+		UserID, provisioned, uErr := imp.resolveUser(u.Email)
+		if uErr != nil {
+			return uErr
+		}
+		Provisioned = provisioned
+
+		_, err := imp.tx.Exec(
+			`INSERT INTO team_user (team_id, user_id, role) VALUES ($1, $2, $3);`,
+			TeamID, UserID, u.Role,
+		)
+		return err
+	})
+	if err != nil {
+		Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: false, Detail: err.Error()})
+		return
+	}
+
+	detail := "added"
+	if Provisioned {
+		detail = "provisional account created and added"
+	}
+	Result.Rows = append(Result.Rows, model.OrgImportRowResult{Row: row, Success: true, Detail: detail})
+}
+
+// resolveUser finds an existing user by email, or creates a provisional account with a random
+// password for them if none exists, going through
+// the same CreateUserRegistered path the invite-accept flow uses rather than a second,
+// divergent INSERT that could drift out of sync with it (password hash, id generation, etc).
+// CreateUserRegistered isn't tx-aware, so a provisioned account isn't undone if the rest of
+// this row's SAVEPOINT rolls back -- the same non-transactional tradeoff AcceptDepartmentInvite
+// already makes when it provisions a user outside of a transaction.
+func (imp *orgImporter) resolveUser(Email string) (string, bool, error) {
+	var UserID string
+	e := imp.tx.QueryRow(`SELECT id FROM users WHERE email = $1;`, Email).Scan(&UserID)
+	if e == nil {
+		return UserID, false, nil
+	}
+
+	password, pwErr := randomString(32)
+	if pwErr != nil {
+		return "", false, pwErr
+	}
+
+	User, _, createErr := imp.db.CreateUserRegistered(Email, Email, password, "")
+	if createErr != nil {
+		return "", false, createErr
+	}
+
+	return User.Id, true, nil
+}
@@ -0,0 +1,68 @@
+package db
+
+import (
+	"errors"
+	"log"
+
+	"github.com/StevenWeathers/thunderdome-planning-poker/model"
+)
+
+// RBACPolicyList gets the full set of RBAC policies, used by auth/rbac.Authorizer to
+// populate its in-memory cache
+func (d *Database) RBACPolicyList() ([]model.RBACPolicy, error) {
+	var policies = make([]model.RBACPolicy, 0)
+
+	rows, err := d.db.Query(
+		`SELECT id, role, resource, action, allow, created_date, updated_date FROM rbac_policies ORDER BY role, resource, action;`,
+	)
+	if err != nil {
+		log.Println(err)
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var p model.RBACPolicy
+		if err := rows.Scan(&p.Id, &p.Role, &p.Resource, &p.Action, &p.Allow, &p.CreatedDate, &p.UpdatedDate); err != nil {
+			log.Println(err)
+			continue
+		}
+		policies = append(policies, p)
+	}
+
+	return policies, nil
+}
+
+// RBACPolicyUpsert creates or updates the allow/deny decision for a role/resource/action tuple
+func (d *Database) RBACPolicyUpsert(Role string, Resource string, Action string, Allow bool) (*model.RBACPolicy, error) {
+	p := &model.RBACPolicy{
+		Role:     Role,
+		Resource: Resource,
+		Action:   Action,
+		Allow:    Allow,
+	}
+
+	e := d.db.QueryRow(`
+		INSERT INTO rbac_policies (role, resource, action, allow)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (role, resource, action) DO UPDATE SET allow = $4, updated_date = NOW()
+		RETURNING id, created_date, updated_date;`,
+		Role, Resource, Action, Allow,
+	).Scan(&p.Id, &p.CreatedDate, &p.UpdatedDate)
+	if e != nil {
+		log.Println(e)
+		return nil, errors.New("unable to upsert rbac policy")
+	}
+
+	return p, nil
+}
+
+// RBACPolicyDelete removes a policy, reverting the role/resource/action tuple to the default deny
+func (d *Database) RBACPolicyDelete(PolicyID string) error {
+	if _, err := d.db.Exec(`DELETE FROM rbac_policies WHERE id = $1;`, PolicyID); err != nil {
+		log.Println(err)
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,19 @@
+package model
+
+import "time"
+
+// APIKey structure
+type APIKey struct {
+	Id          string    `json:"id"`
+	Name        string    `json:"name"`
+	Key         string    `json:"apiKey"`
+	UserId      string    `json:"userId"`
+	Prefix      string    `json:"prefix"`
+	Active      bool      `json:"active"`
+	CreatedDate time.Time  `json:"createdDate"`
+	UpdatedDate time.Time  `json:"updatedDate"`
+	Scopes      []string   `json:"scopes"`
+	ExpiresAt   *time.Time `json:"expiresAt"`
+	LastUsedAt  *time.Time `json:"lastUsedAt"`
+	RevokedAt   *time.Time `json:"revokedAt"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// Department structure
+type Department struct {
+	Id                 string    `json:"id"`
+	Name               string    `json:"name"`
+	OrganizationId     string    `json:"organizationId"`
+	ParentDepartmentId string    `json:"parentDepartmentId"`
+	CreatedDate        time.Time `json:"createdDate"`
+	UpdatedDate        time.Time `json:"updatedDate"`
+}
+
+// DepartmentUser structure
+type DepartmentUser struct {
+	Id    string `json:"id"`
+	Name  string `json:"name"`
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// DepartmentTree structure represents a department and its nested sub-departments
+type DepartmentTree struct {
+	Department
+	Children []*DepartmentTree `json:"children"`
+}
@@ -0,0 +1,25 @@
+package model
+
+import "time"
+
+// Invite represents a single- or multi-use tokenized invite to join a department (and
+// optionally one of its teams) without the invitee needing an existing account
+type Invite struct {
+	Id           string     `json:"id"`
+	DepartmentId string     `json:"departmentId"`
+	TeamId       string     `json:"teamId"`
+	Role         string     `json:"role"`
+	MaxUses      int        `json:"maxUses"`
+	UseCount     int        `json:"useCount"`
+	ExpiresAt    time.Time  `json:"expiresAt"`
+	RevokedAt    *time.Time `json:"revokedAt"`
+	CreatedDate  time.Time  `json:"createdDate"`
+}
+
+// InviteDetails is the public, unauthenticated view of an invite shown on the signup page
+type InviteDetails struct {
+	OrganizationName string `json:"organizationName"`
+	DepartmentName   string `json:"departmentName"`
+	TeamName         string `json:"teamName"`
+	Role             string `json:"role"`
+}
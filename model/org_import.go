@@ -0,0 +1,41 @@
+package model
+
+// OrgImportUser describes a user (by email) to add to a department or team during import
+type OrgImportUser struct {
+	Email string `json:"email"`
+	Role  string `json:"role"`
+}
+
+// OrgImportTeam describes a department team, and its users, to create during import
+type OrgImportTeam struct {
+	Name  string          `json:"name"`
+	Users []OrgImportUser `json:"users"`
+}
+
+// OrgImportDepartment describes a department, its teams, and its users to create during import
+type OrgImportDepartment struct {
+	Name  string          `json:"name"`
+	Users []OrgImportUser `json:"users"`
+	Teams []OrgImportTeam `json:"teams"`
+}
+
+// OrgImportSpec is the full org structure (departments, teams, user assignments) to
+// bootstrap in a single call, e.g. from an HR export
+type OrgImportSpec struct {
+	Departments []OrgImportDepartment `json:"departments"`
+}
+
+// OrgImportRowResult captures the outcome of provisioning a single row of an OrgImportSpec.
+// Notified is only meaningful for rows that provisioned a new account -- it's false for
+// everything else, and false for a provisioning row means the invite email was not delivered.
+type OrgImportRowResult struct {
+	Row      string `json:"row"`
+	Success  bool   `json:"success"`
+	Detail   string `json:"detail,omitempty"`
+	Notified bool   `json:"notified"`
+}
+
+// OrgImportResult is the per-row success/error report returned from Database.OrganizationImport
+type OrgImportResult struct {
+	Rows []OrgImportRowResult `json:"rows"`
+}
@@ -0,0 +1,14 @@
+package model
+
+import "time"
+
+// RBACPolicy grants (or explicitly denies) a role the ability to perform an action on a resource
+type RBACPolicy struct {
+	Id          string    `json:"id"`
+	Role        string    `json:"role"`
+	Resource    string    `json:"resource"`
+	Action      string    `json:"action"`
+	Allow       bool      `json:"allow"`
+	CreatedDate time.Time `json:"createdDate"`
+	UpdatedDate time.Time `json:"updatedDate"`
+}